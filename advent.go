@@ -4,7 +4,10 @@
 package advent
 
 import (
+	"bufio"
 	"errors"
+	"io"
+	"math"
 	"strings"
 	"unicode"
 )
@@ -31,8 +34,50 @@ type Config struct {
 	// Abbreviations is a map of custom abbreviations to prevent sentence splitting.
 	// If nil, a default list of English abbreviations is used.
 	Abbreviations map[string]bool
+
+	// LineBreakAlgorithm selects the word-wrapping strategy used when
+	// RespectMaxLineLength is in effect and SentenceBreak is false.
+	// The zero value is Greedy.
+	LineBreakAlgorithm LineBreakAlgorithm
+
+	// Tolerance is the maximum adjustment ratio a non-final line may have
+	// under the Optimal algorithm before its breakpoint is discarded as
+	// too loose. If zero, a default tolerance of 4 is used.
+	Tolerance float64
+
+	// LinePenalty is added to the demerits of every line under the Optimal
+	// algorithm, discouraging solutions with more lines than necessary.
+	// If zero, a default line penalty is used.
+	LinePenalty float64
+
+	// Hyphenated, when true, allows the Optimal algorithm to break a word
+	// at an existing hyphen if doing so improves the paragraph's overall
+	// line breaks.
+	Hyphenated bool
+
+	// FormatFlowed, when true, treats the input as format=flowed (RFC 3676)
+	// text: trailing-space-terminated lines are soft breaks joined before
+	// ventilation, and the ventilated output is re-emitted in
+	// format=flowed form, with soft breaks marked by a trailing space and
+	// lines beginning with '>' or "From " space-stuffed.
+	FormatFlowed bool
 }
 
+// LineBreakAlgorithm selects between word-wrapping strategies for
+// RespectMaxLineLength.
+type LineBreakAlgorithm int
+
+const (
+	// Greedy fills each line with as many words as fit before wrapping,
+	// the ventilator's original behavior.
+	Greedy LineBreakAlgorithm = iota
+
+	// Optimal applies Knuth-Plass paragraph breaking, choosing the set of
+	// breakpoints that minimizes raggedness across the whole paragraph
+	// rather than line by line.
+	Optimal
+)
+
 var (
 	errUnterminatedMarkup = errors.New("unterminated inline markup span")
 
@@ -57,7 +102,12 @@ func Ventilate(input string, cfg Config) (string, error) {
 	// Preserve trailing newline information.
 	hasTrailingNewline := strings.HasSuffix(input, "\n") || strings.HasSuffix(input, "\r\n")
 
-	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+	normalized := strings.ReplaceAll(input, "\r\n", "\n")
+	if cfg.FormatFlowed {
+		normalized = unfoldFlowed(normalized)
+	}
+
+	lines := strings.Split(normalized, "\n")
 	var processedBlocks []string
 	var blockBuffer []string
 
@@ -102,8 +152,248 @@ func Ventilate(input string, cfg Config) (string, error) {
 	return output, nil
 }
 
+// Unventilate collapses ventilated Markdown prose back into flowing
+// paragraphs: the lines of a reflowable block are joined into a single
+// paragraph, optionally rewrapped to MaxLineLength, while code fences,
+// headings, lists, and blockquote structure are left untouched. It is the
+// inverse of Ventilate on well-formed input.
+func Unventilate(input string, cfg Config) (string, error) {
+	if input == "" {
+		return "", nil
+	}
+
+	if err := checkUnterminatedMarkup(input); err != nil {
+		return "", err
+	}
+
+	hasTrailingNewline := strings.HasSuffix(input, "\n") || strings.HasSuffix(input, "\r\n")
+
+	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+	var processedBlocks []string
+	var blockBuffer []string
+
+	processBlock := func() error {
+		if len(blockBuffer) > 0 {
+			processed, err := unventilateBlock(blockBuffer, cfg)
+			if err != nil {
+				return err
+			}
+			processedBlocks = append(processedBlocks, processed)
+			blockBuffer = nil
+		}
+		return nil
+	}
+
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			if err := processBlock(); err != nil {
+				return "", err
+			}
+		} else {
+			blockBuffer = append(blockBuffer, line)
+		}
+	}
+
+	if err := processBlock(); err != nil {
+		return "", err
+	}
+
+	output := strings.Join(processedBlocks, "\n\n")
+
+	if hasTrailingNewline && !strings.HasSuffix(output, "\n") {
+		output += "\n"
+	}
+
+	return output, nil
+}
+
+// Ventilator streams Ventilate's block-by-block reflow over an io.Reader,
+// writing each ventilated block to an io.Writer as soon as it is complete
+// rather than buffering the whole document in memory, so large documents
+// can be ventilated holding only one block at a time. A Ventilator may be reused for
+// multiple streams via Reset.
+type Ventilator struct {
+	cfg         Config
+	markupLevel int
+}
+
+// NewVentilator returns a Ventilator configured with cfg.
+func NewVentilator(cfg Config) *Ventilator {
+	return &Ventilator{cfg: cfg}
+}
+
+// Reset reconfigures v with cfg and clears any state left over from a
+// previous stream, so v may be reused for a new one.
+func (v *Ventilator) Reset(cfg Config) {
+	v.cfg = cfg
+	v.markupLevel = 0
+}
+
+// Ventilate reads r block by block, ventilating each block with v's Config
+// and writing it to w as soon as it is complete. CriticMarkup/EditML
+// nesting level is carried across blocks, rather than checked in a single
+// upfront pass, so an unterminated span is still caught without ever
+// buffering the whole stream in memory. Because there's no upfront pass,
+// a span that never closes is only reported once r is exhausted, after
+// any earlier well-formed blocks have already been written to w; a stray
+// closing brace with no matching open is still reported immediately. If
+// v's Config has FormatFlowed set, each block's soft-broken lines are
+// unfolded before ventilation, the same as Ventilate does up front.
+func (v *Ventilator) Ventilate(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+
+	var blockLines []string
+	wroteBlock := false
+	trailingNewline := false
+
+	flush := func() error {
+		if len(blockLines) == 0 {
+			return nil
+		}
+		if wroteBlock {
+			if _, err := io.WriteString(w, "\n\n"); err != nil {
+				return err
+			}
+		}
+		linesToProcess := blockLines
+		if v.cfg.FormatFlowed {
+			linesToProcess = strings.Split(unfoldFlowed(strings.Join(blockLines, "\n")), "\n")
+		}
+		processed, err := ventilateBlock(linesToProcess, v.cfg)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, processed); err != nil {
+			return err
+		}
+		wroteBlock = true
+		blockLines = nil
+		return nil
+	}
+
+	for {
+		rawLine, readErr := br.ReadString('\n')
+		if rawLine == "" && readErr == io.EOF {
+			break
+		}
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+
+		trailingNewline = strings.HasSuffix(rawLine, "\n")
+		line := strings.TrimSuffix(strings.TrimSuffix(rawLine, "\n"), "\r")
+
+		if err := v.trackMarkupLevel(line); err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(line) == "" {
+			if err := flush(); err != nil {
+				return err
+			}
+		} else {
+			blockLines = append(blockLines, line)
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+	if v.markupLevel != 0 {
+		return errUnterminatedMarkup
+	}
+	if trailingNewline {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// trackMarkupLevel updates v's running CriticMarkup/EditML nesting level
+// with the braces in line, returning errUnterminatedMarkup immediately on
+// a closing brace with no matching open.
+func (v *Ventilator) trackMarkupLevel(line string) error {
+	for _, r := range line {
+		if r == '{' {
+			v.markupLevel++
+		} else if r == '}' {
+			if v.markupLevel == 0 {
+				return errUnterminatedMarkup
+			}
+			v.markupLevel--
+		}
+	}
+	return nil
+}
+
+// VentilateStream ventilates r block by block and writes the result to w,
+// without buffering the whole document in memory. See Ventilator for the
+// streaming tradeoffs around detecting an unterminated markup span.
+func VentilateStream(r io.Reader, w io.Writer, cfg Config) error {
+	return NewVentilator(cfg).Ventilate(r, w)
+}
+
+// unventilateBlock collapses a single ventilated block back into a flowing
+// paragraph, mirroring ventilateBlock's quote and non-prose classification
+// but joining prose lines into one logical paragraph: a ventilated line
+// break carries no meaning of its own, so there is nothing left to
+// preserve, except where cfg.Hyphenated means the break may itself be a
+// Knuth-Plass hyphen break inside a word (see joinOptimalHyphenatedLines).
+func unventilateBlock(blockLines []string, cfg Config) (string, error) {
+	if prefix, _, _, ok := quotePrefix(blockLines[0]); ok {
+		if inner, uniform := stripQuotePrefix(blockLines, prefix); uniform {
+			collapsed, err := unventilateBlock(inner, cfg)
+			if err != nil {
+				return "", err
+			}
+			return reapplyQuotePrefix(collapsed, prefix), nil
+		}
+	}
+
+	firstLineTrimmed := strings.TrimSpace(blockLines[0])
+	if isNonProseBlock(firstLineTrimmed) || strings.HasPrefix(firstLineTrimmed, "```") {
+		return strings.Join(blockLines, "\n"), nil
+	}
+
+	var paragraph string
+	if cfg.Hyphenated {
+		paragraph = joinOptimalHyphenatedLines(blockLines)
+	} else {
+		paragraph = strings.Join(blockLines, " ")
+	}
+
+	if cfg.RespectMaxLineLength && cfg.MaxLineLength > 0 {
+		if cfg.LineBreakAlgorithm == Optimal {
+			return ventilateByLineLengthOptimal(paragraph, cfg)
+		}
+		return ventilateByLineLength(paragraph, cfg.MaxLineLength)
+	}
+
+	return paragraph, nil
+}
+
 // ventilateBlock determines the block type and processes it accordingly.
 func ventilateBlock(blockLines []string, cfg Config) (string, error) {
+	// A block whose lines share a common quote prefix ("> ", ">> ", "> > ",
+	// ...) is a reflowable quoted block: strip the prefix, ventilate the
+	// inner prose with all normal rules, then reapply the prefix to every
+	// emitted line.
+	if prefix, _, _, ok := quotePrefix(blockLines[0]); ok {
+		if inner, uniform := stripQuotePrefix(blockLines, prefix); uniform {
+			reflowed, err := ventilateBlock(inner, cfg)
+			if err != nil {
+				return "", err
+			}
+			return reapplyQuotePrefix(reflowed, prefix), nil
+		}
+	}
+
 	// A block is non-prose if its first line indicates a non-prose type.
 	firstLineTrimmed := strings.TrimSpace(blockLines[0])
 
@@ -112,22 +402,136 @@ func ventilateBlock(blockLines []string, cfg Config) (string, error) {
 		return strings.Join(blockLines, "\n"), nil
 	}
 
-	// Join lines with care, preserving meaningful breaks like those after a colon.
-	var paraBuilder strings.Builder
-	for i, line := range blockLines {
-		paraBuilder.WriteString(line)
-		if i < len(blockLines)-1 {
-			// If a line ends with a colon, preserve the newline.
-			// Otherwise, join with a space to merge wrapped lines.
+	out, err := ventilateParagraph(joinBlockLines(blockLines), cfg)
+	if err != nil {
+		return "", err
+	}
+	if cfg.FormatFlowed {
+		out = formatFlowedLines(out)
+	}
+	return out, nil
+}
+
+// joinBlockLines joins a prose block's physical lines into a single
+// logical paragraph the way Ventilate treats line breaks: an ordinary
+// wrapped line joins its neighbor with a space, but a line ending in ':'
+// keeps its break, since that's conventionally an intentional structural
+// break (e.g. introducing a list) rather than mid-sentence wrapping.
+// scanProse builds the same joined text, byte-for-byte, so sentence
+// detection agrees between Ventilate and Segmenter.
+func joinBlockLines(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		b.WriteString(line)
+		if i < len(lines)-1 {
 			if strings.HasSuffix(strings.TrimSpace(line), ":") {
-				paraBuilder.WriteString("\n")
+				b.WriteString("\n")
 			} else {
-				paraBuilder.WriteString(" ")
+				b.WriteString(" ")
 			}
 		}
 	}
+	return b.String()
+}
 
-	return ventilateParagraph(paraBuilder.String(), cfg)
+// quotePrefix reports the leading quote-depth marker of line, e.g. "> ",
+// ">> ", or "> > ". ok is false if line is not quote-prefixed. rest is the
+// remainder of the line after the prefix.
+func quotePrefix(line string) (prefix string, depth int, rest string, ok bool) {
+	i := 0
+	for i < len(line) && line[i] == '>' {
+		depth++
+		i++
+		if i < len(line) && line[i] == ' ' {
+			i++
+		}
+	}
+	if depth == 0 {
+		return "", 0, "", false
+	}
+	return line[:i], depth, line[i:], true
+}
+
+// stripQuotePrefix removes prefix from every line, reporting false if any
+// line does not share it (a mix of quote depths, which falls back to the
+// non-prose passthrough path).
+func stripQuotePrefix(lines []string, prefix string) ([]string, bool) {
+	stripped := make([]string, len(lines))
+	for i, line := range lines {
+		if !strings.HasPrefix(line, prefix) {
+			return nil, false
+		}
+		stripped[i] = line[len(prefix):]
+	}
+	return stripped, true
+}
+
+// reapplyQuotePrefix prepends prefix to every line of text.
+func reapplyQuotePrefix(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = strings.TrimRight(prefix, " ")
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unfoldFlowed merges format=flowed (RFC 3676) soft line breaks, lines
+// ending in a trailing space, into the following line before ventilation,
+// so a hard-wrapped flowed paragraph is treated as a single logical line.
+// Continuation lines only merge with a preceding line that shares the same
+// quote prefix.
+func unfoldFlowed(input string) string {
+	lines := strings.Split(input, "\n")
+	var out []string
+	for i := 0; i < len(lines); i++ {
+		prefix, _, body, hasPrefix := quotePrefix(lines[i])
+		if !hasPrefix {
+			body = lines[i]
+		}
+		for body != "-- " && strings.HasSuffix(body, " ") && i+1 < len(lines) {
+			if strings.TrimSpace(lines[i]) == "" || strings.TrimSpace(lines[i+1]) == "" {
+				break // a blank line always ends the block, soft break or not
+			}
+			nextPrefix, _, nextBody, nextHasPrefix := quotePrefix(lines[i+1])
+			if !nextHasPrefix {
+				nextBody = lines[i+1]
+			}
+			if hasPrefix != nextHasPrefix || prefix != nextPrefix {
+				break
+			}
+			body += nextBody
+			i++
+		}
+		if hasPrefix {
+			out = append(out, prefix+body)
+		} else {
+			out = append(out, body)
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// formatFlowedLines rewrites a ventilated paragraph for format=flowed (RFC
+// 3676) transport: all but the last line get a single trailing space
+// marking a soft break, and any line beginning with '>' or "From " is
+// space-stuffed with a leading space so a flowed-aware reader doesn't
+// mistake it for a quote marker or mbox delimiter.
+func formatFlowedLines(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ">") || strings.HasPrefix(line, "From ") {
+			line = " " + line
+		}
+		if i < len(lines)-1 && line != "-- " {
+			line += " "
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
 }
 
 // ventilateParagraph handles the core logic for a single prose paragraph.
@@ -136,6 +540,9 @@ func ventilateParagraph(p string, cfg Config) (string, error) {
 		return ventilateBySentence(p, cfg)
 	}
 	if cfg.RespectMaxLineLength && cfg.MaxLineLength > 0 {
+		if cfg.LineBreakAlgorithm == Optimal {
+			return ventilateByLineLengthOptimal(p, cfg)
+		}
 		return ventilateByLineLength(p, cfg.MaxLineLength)
 	}
 	return p, nil
@@ -270,6 +677,257 @@ func ventilateByLineLength(p string, maxLen int) (string, error) {
 	return result.String(), nil
 }
 
+// Default tuning knobs and demerit costs for ventilateByLineLengthOptimal,
+// following the Knuth-Plass paragraph-breaking algorithm.
+const (
+	defaultTolerance   = 4.0
+	defaultLinePenalty = 10.0
+	hyphenPenalty      = 50.0
+	flaggedPenalty     = 3000.0
+)
+
+// boundaryKind classifies the gap between two adjacent boxes in an
+// optimally-broken paragraph.
+type boundaryKind int
+
+const (
+	// boundarySpace is an ordinary interword space: a legal, stretchable
+	// and shrinkable breakpoint.
+	boundarySpace boundaryKind = iota
+	// boundaryHyphen is a legal breakpoint at an existing hyphen inside a
+	// word, with no visible gap and no stretch or shrink.
+	boundaryHyphen
+	// boundaryTight glues two boxes together with no legal breakpoint
+	// between them.
+	boundaryTight
+)
+
+// tokenizeOptimalBoxes splits a paragraph into unbreakable boxes (words, or
+// CriticMarkup/EditML spans kept intact) along with the boundary kind
+// between each consecutive pair of boxes. If hyphenated is true, words
+// containing a hyphen are split into sub-boxes joined by boundaryHyphen so
+// the optimal algorithm may break there.
+func tokenizeOptimalBoxes(p string, hyphenated bool) ([]string, []boundaryKind, error) {
+	words, err := tokenizeBoxes(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var boxes []string
+	var boundaries []boundaryKind
+	for wi, word := range words {
+		if wi > 0 {
+			boundaries = append(boundaries, boundarySpace)
+		}
+		if !hyphenated || !strings.ContainsRune(word, '-') || strings.ContainsRune(word, '{') {
+			boxes = append(boxes, word)
+			continue
+		}
+		parts := strings.SplitAfter(word, "-")
+		for pi, part := range parts {
+			if part == "" {
+				continue
+			}
+			if pi > 0 {
+				boundaries = append(boundaries, boundaryHyphen)
+			}
+			boxes = append(boxes, part)
+		}
+	}
+	return boxes, boundaries, nil
+}
+
+// tokenizeBoxes splits a paragraph into unbreakable boxes on whitespace,
+// keeping CriticMarkup/EditML spans ({...}) intact as a single box even
+// when they contain internal spaces.
+func tokenizeBoxes(p string) ([]string, error) {
+	var boxes []string
+	var box strings.Builder
+
+	flush := func() {
+		if box.Len() > 0 {
+			boxes = append(boxes, box.String())
+			box.Reset()
+		}
+	}
+
+	for i := 0; i < len(p); {
+		if unicode.IsSpace(rune(p[i])) {
+			flush()
+			i++
+			continue
+		}
+		if p[i] == '{' {
+			end, ok := findMarkupEnd(p, i)
+			if !ok {
+				return nil, errUnterminatedMarkup
+			}
+			box.WriteString(p[i : end+1])
+			i = end + 1
+			continue
+		}
+		box.WriteByte(p[i])
+		i++
+	}
+	flush()
+
+	return boxes, nil
+}
+
+// ventilateByLineLengthOptimal wraps a paragraph at maxLen using
+// Knuth-Plass paragraph breaking. It treats words (and CriticMarkup/EditML
+// spans) as boxes, interword spaces as stretchable/shrinkable glue, and
+// picks the set of breakpoints that minimizes total demerits across the
+// whole paragraph rather than line by line.
+func ventilateByLineLengthOptimal(p string, cfg Config) (string, error) {
+	boxes, boundaries, err := tokenizeOptimalBoxes(p, cfg.Hyphenated)
+	if err != nil {
+		return "", err
+	}
+	n := len(boxes)
+	if n == 0 {
+		return "", nil
+	}
+
+	tolerance := cfg.Tolerance
+	if tolerance <= 0 {
+		tolerance = defaultTolerance
+	}
+	linePenalty := cfg.LinePenalty
+	if linePenalty == 0 {
+		linePenalty = defaultLinePenalty
+	}
+	maxLen := float64(cfg.MaxLineLength)
+
+	const unreachable = math.MaxFloat64
+
+	// demerits[j] is the minimal total demerits of a breakpoint ending the
+	// line at boxes[j-1]; prev[j] is the breakpoint it was reached from.
+	demerits := make([]float64, n+1)
+	prev := make([]int, n+1)
+	flaggedEnd := make([]bool, n+1)
+	for j := 1; j <= n; j++ {
+		demerits[j] = unreachable
+	}
+
+	for j := 1; j <= n; j++ {
+		final := j == n
+		if !final && boundaries[j-1] == boundaryTight {
+			continue // not a legal breakpoint
+		}
+		lineFlagged := !final && boundaries[j-1] == boundaryHyphen
+
+		width, stretch, shrink := 0, 0, 0
+		for i := j - 1; i >= 0; i-- {
+			width += len(boxes[i])
+			if i < j-1 && boundaries[i] == boundarySpace {
+				width++
+				stretch++
+				shrink++
+			}
+			if demerits[i] == unreachable {
+				continue
+			}
+
+			var r float64
+			switch {
+			case float64(width) == maxLen:
+				r = 0
+			case float64(width) < maxLen:
+				if stretch == 0 {
+					if !final {
+						continue
+					}
+					r = 0
+				} else {
+					r = (maxLen - float64(width)) / float64(stretch)
+				}
+			default:
+				if shrink == 0 {
+					continue
+				}
+				r = (maxLen - float64(width)) / float64(shrink)
+			}
+
+			if r < -1 || (!final && r > tolerance) {
+				continue
+			}
+
+			badness := 1 + 100*math.Pow(math.Abs(r), 3) + linePenalty
+			if lineFlagged {
+				badness += hyphenPenalty
+			}
+			d := math.Pow(badness, 2)
+			if lineFlagged && flaggedEnd[i] {
+				d += flaggedPenalty
+			}
+
+			if total := demerits[i] + d; total < demerits[j] {
+				demerits[j] = total
+				prev[j] = i
+				flaggedEnd[j] = lineFlagged
+			}
+		}
+	}
+
+	if demerits[n] == unreachable {
+		// No feasible set of breakpoints (e.g. a single word longer than
+		// MaxLineLength); fall back to greedy wrapping.
+		return ventilateByLineLength(p, cfg.MaxLineLength)
+	}
+
+	var breakpoints []int
+	for j := n; j > 0; j = prev[j] {
+		breakpoints = append([]int{j}, breakpoints...)
+	}
+
+	var result strings.Builder
+	start := 0
+	for li, end := range breakpoints {
+		if li > 0 {
+			result.WriteRune('\n')
+		}
+		for bi := start; bi < end; bi++ {
+			if bi > start && boundaries[bi-1] == boundarySpace {
+				result.WriteRune(' ')
+			}
+			result.WriteString(boxes[bi])
+		}
+		start = end
+	}
+
+	return result.String(), nil
+}
+
+// joinOptimalHyphenatedLines joins a block's ventilated lines back into a
+// single paragraph, the inverse of ventilateByLineLengthOptimal's
+// Hyphenated mode: a line ending in a hyphen break (see boundaryHyphen)
+// joins its neighbor with no separator, since the hyphen is part of the
+// word rather than a space; every other line joins with a space, as
+// strings.Join(blockLines, " ") would.
+func joinOptimalHyphenatedLines(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		b.WriteString(line)
+		if i < len(lines)-1 && !isHyphenBreak(line) {
+			b.WriteString(" ")
+		}
+	}
+	return b.String()
+}
+
+// isHyphenBreak reports whether line looks like it was wrapped at a
+// Knuth-Plass hyphen breakpoint (boundaryHyphen): ending in a single
+// hyphen directly after a letter, as opposed to a double hyphen or em
+// dash used as punctuation.
+func isHyphenBreak(line string) bool {
+	if !strings.HasSuffix(line, "-") || len(line) < 2 {
+		return false
+	}
+	prev := rune(line[len(line)-2])
+	return unicode.IsLetter(prev)
+}
+
 func checkUnterminatedMarkup(s string) error {
 	level := 0
 	for _, r := range s {
@@ -295,26 +953,377 @@ func isNonProseBlock(line string) bool {
 	if line == "" {
 		return false
 	}
-	if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ">") || strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "---") {
+	if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ">") || strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ") || strings.HasPrefix(line, "---") || strings.HasPrefix(line, "<") {
 		return true
 	}
-	if i := strings.Index(line, ". "); i > 0 {
-		numPart := line[:i]
-		if len(numPart) == 0 {
+	return isOrderedListItem(line)
+}
+
+// isOrderedListItem reports whether line begins with a numeric list marker
+// such as "1. ".
+func isOrderedListItem(line string) bool {
+	i := strings.Index(line, ". ")
+	if i <= 0 {
+		return false
+	}
+	numPart := line[:i]
+	for _, r := range numPart {
+		if !unicode.IsDigit(r) {
 			return false
 		}
-		isAllDigits := true
-		for _, r := range numPart {
-			if !unicode.IsDigit(r) {
-				isAllDigits = false
+	}
+	return true
+}
+
+// SegmentKind identifies the type of a Segment yielded by a Segmenter.
+type SegmentKind int
+
+const (
+	// SegProse is a run of ordinary reflowable text within a prose block.
+	SegProse SegmentKind = iota
+	// SegHeading is a block whose first line begins with "#" (an ATX
+	// heading) or "---". A setext heading, whose "---" underline follows
+	// its text, is not recognized and is scanned as prose.
+	SegHeading
+	// SegList is a bullet or numbered list item block.
+	SegList
+	// SegBlockquote is an email- or Markdown-style quoted block. Depth and
+	// Prefix report its quote nesting and literal marker.
+	SegBlockquote
+	// SegFencedCode is a ``` fenced code block.
+	SegFencedCode
+	// SegHTMLBlock is a block whose first line begins with an HTML tag.
+	SegHTMLBlock
+	// SegEditMLSpan is a CriticMarkup/EditML span ({...}) within a prose
+	// block, kept intact as a single unbreakable unit.
+	SegEditMLSpan
+	// SegAbbrev is a recognized abbreviation (e.g. "Mr.") within a prose
+	// block that does not end a sentence.
+	SegAbbrev
+	// SegSentenceEnd is the terminal punctuation, and any trailing closing
+	// marks, of a sentence within a prose block.
+	SegSentenceEnd
+	// SegBlankLine is a blank (empty or whitespace-only) line, including
+	// one before the first block or after the last.
+	SegBlankLine
+)
+
+// Segment is a single classified unit of input yielded by a Segmenter.
+// Start and End are byte offsets into the original input passed to
+// NewSegmenter, with End exclusive. Depth and Prefix are only meaningful
+// for SegBlockquote segments: Depth is the quote nesting level and Prefix
+// is the literal leading marker (e.g. "> ", ">> ").
+type Segment struct {
+	Kind   SegmentKind
+	Start  int
+	End    int
+	Text   string
+	Depth  int
+	Prefix string
+}
+
+// Segmenter scans Markdown-formatted prose into a stream of classified
+// Segment values, without reflowing anything. It is a read-only
+// classifier, not a view onto Ventilate/Unventilate's own pipeline: block
+// classification (quote, heading, list, fenced code, HTML) follows the
+// same line-prefix rules Ventilate and Unventilate apply, and a
+// multi-line prose block is joined the way Ventilate joins it, so those
+// decisions agree: a block a Segmenter reports as SegHTMLBlock is one
+// Ventilate passes through untouched, and the text a multi-line prose
+// block's sentence boundaries are computed over is exactly the paragraph
+// Ventilate would reflow. Sentence-boundary detection within a prose
+// block is its own implementation of ventilateBySentence's rules, kept in
+// sync by hand rather than shared code, and it differs in two ways: it
+// recognizes only the default abbreviations, and it reports no sentence
+// end after a CriticMarkup/EditML span that is followed by a capital
+// letter, where Ventilate does break.
+type Segmenter struct {
+	segments []Segment
+	pos      int
+	err      error
+}
+
+// NewSegmenter scans input and returns a Segmenter ready to yield its
+// Segment stream via Next or Visit. Like Ventilate and Unventilate, it
+// validates input's CriticMarkup/EditML nesting up front; if input
+// contains an unterminated span, every call to Next or Visit returns
+// errUnterminatedMarkup rather than silently treating the stray brace as
+// ordinary prose text.
+func NewSegmenter(input string) *Segmenter {
+	if err := checkUnterminatedMarkup(input); err != nil {
+		return &Segmenter{err: err}
+	}
+	return &Segmenter{segments: scanSegments(input)}
+}
+
+// Next returns the next Segment in the stream. Once the stream is
+// exhausted, it returns io.EOF. If input failed validation in
+// NewSegmenter, it returns that error on every call instead.
+func (s *Segmenter) Next() (Segment, error) {
+	if s.err != nil {
+		return Segment{}, s.err
+	}
+	if s.pos >= len(s.segments) {
+		return Segment{}, io.EOF
+	}
+	seg := s.segments[s.pos]
+	s.pos++
+	return seg, nil
+}
+
+// Visit calls fn for every remaining Segment in the stream, in order,
+// stopping at the first error fn returns.
+func (s *Segmenter) Visit(fn func(Segment) error) error {
+	for {
+		seg, err := s.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(seg); err != nil {
+			return err
+		}
+	}
+}
+
+// rawLine is an input line together with its byte offsets in the original
+// input, used while scanning to give every Segment accurate Start/End
+// values.
+type rawLine struct {
+	text  string
+	start int
+	end   int
+}
+
+// scanSegments splits input into blank-line-delimited blocks, each
+// classified and expanded into one or more Segments.
+func scanSegments(input string) []Segment {
+	var lines []rawLine
+	pos := 0
+	for {
+		nl := strings.IndexByte(input[pos:], '\n')
+		if nl == -1 {
+			lines = append(lines, rawLine{input[pos:], pos, len(input)})
+			break
+		}
+		lines = append(lines, rawLine{input[pos : pos+nl], pos, pos + nl})
+		pos += nl + 1
+	}
+
+	var segments []Segment
+	var blockLines []rawLine
+
+	flushBlock := func() {
+		if len(blockLines) > 0 {
+			segments = append(segments, classifyBlock(blockLines)...)
+			blockLines = nil
+		}
+	}
+
+	for _, l := range lines {
+		if strings.TrimSpace(l.text) == "" {
+			flushBlock()
+			segments = append(segments, Segment{Kind: SegBlankLine, Start: l.start, End: l.end, Text: l.text})
+			continue
+		}
+		blockLines = append(blockLines, l)
+	}
+	flushBlock()
+
+	return segments
+}
+
+// joinRawLines reassembles the original text spanned by lines, including
+// the newlines between them.
+func joinRawLines(lines []rawLine) string {
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		texts[i] = l.text
+	}
+	return strings.Join(texts, "\n")
+}
+
+// classifyBlock classifies a single blank-line-delimited block of lines
+// into one or more Segments, matching the same rules ventilateBlock uses
+// to decide how a block should be reflowed.
+func classifyBlock(lines []rawLine) []Segment {
+	start, end := lines[0].start, lines[len(lines)-1].end
+	text := joinRawLines(lines)
+	first := strings.TrimSpace(lines[0].text)
+
+	if prefix, depth, _, ok := quotePrefix(lines[0].text); ok {
+		uniform := true
+		for _, l := range lines {
+			if !strings.HasPrefix(l.text, prefix) {
+				uniform = false
 				break
 			}
 		}
-		if isAllDigits {
-			return true
+		if uniform {
+			return []Segment{{Kind: SegBlockquote, Start: start, End: end, Text: text, Depth: depth, Prefix: prefix}}
 		}
 	}
-	return false
+
+	switch {
+	case strings.HasPrefix(first, ">"):
+		prefix, depth, _, _ := quotePrefix(first)
+		return []Segment{{Kind: SegBlockquote, Start: start, End: end, Text: text, Depth: depth, Prefix: prefix}}
+	case strings.HasPrefix(first, "```"):
+		return []Segment{{Kind: SegFencedCode, Start: start, End: end, Text: text}}
+	case strings.HasPrefix(first, "<"):
+		return []Segment{{Kind: SegHTMLBlock, Start: start, End: end, Text: text}}
+	case strings.HasPrefix(first, "#") || strings.HasPrefix(first, "---"):
+		return []Segment{{Kind: SegHeading, Start: start, End: end, Text: text}}
+	case strings.HasPrefix(first, "- ") || strings.HasPrefix(first, "* ") || isOrderedListItem(first):
+		return []Segment{{Kind: SegList, Start: start, End: end, Text: text}}
+	}
+
+	return scanProse(lines)
+}
+
+// scanProse scans a single prose block's lines, joined into one logical
+// paragraph exactly as joinBlockLines would (so a wrapped line and a
+// sentence-ending line break are told apart the same way Ventilate tells
+// them apart), and yields SegProse, SegEditMLSpan, SegAbbrev, and
+// SegSentenceEnd segments across the result. A CriticMarkup/EditML span
+// is only recognized as a single SegEditMLSpan when it opens and closes
+// within the block; otherwise its braces are treated as ordinary prose
+// characters, since checkUnterminatedMarkup has already rejected
+// genuinely unterminated spans before a Segmenter is built.
+func scanProse(lines []rawLine) []Segment {
+	return scanProseText(joinRawLinesForProse(lines), lines[0].start)
+}
+
+// joinRawLinesForProse reconstructs a prose block's lines into the same
+// joined paragraph text joinBlockLines produces, but as a byte-for-byte
+// replacement of the original span: every newline between two lines is
+// overwritten with a single joiner byte (a space, or left as '\n' after
+// a colon), so the result has the exact length and byte offsets of
+// input[lines[0].start:lines[len(lines)-1].end] and scanProseText's
+// Segments can be reported directly against the original input.
+func joinRawLinesForProse(lines []rawLine) string {
+	b := []byte(joinRawLines(lines))
+	offset := 0
+	for i := 0; i < len(lines)-1; i++ {
+		offset += len(lines[i].text)
+		if !strings.HasSuffix(strings.TrimSpace(lines[i].text), ":") {
+			b[offset] = ' '
+		}
+		offset++ // step over the joiner byte itself
+	}
+	return string(b)
+}
+
+// scanProseText scans one already-joined prose paragraph (see
+// joinBlockLines) for SegProse, SegEditMLSpan, SegAbbrev, and
+// SegSentenceEnd segments, mirroring the sentence- and
+// markup-span-detection rules ventilateBySentence uses, except that only
+// defaultAbbreviations are recognized and a span followed by a capital
+// letter does not end a sentence here. Every Segment's
+// Start/End is offset by base so it refers back into the original input
+// passed to NewSegmenter.
+func scanProseText(p string, base int) []Segment {
+	var segments []Segment
+	proseStart := -1
+
+	flushProse := func(end int) {
+		if proseStart >= 0 && end > proseStart {
+			segments = append(segments, Segment{Kind: SegProse, Start: base + proseStart, End: base + end})
+		}
+		proseStart = -1
+	}
+
+	i := 0
+	for i < len(p) {
+		if strings.HasPrefix(p[i:], "...") {
+			if proseStart < 0 {
+				proseStart = i
+			}
+			i += 3
+			continue
+		}
+
+		if p[i] == '{' {
+			if end, ok := findMarkupEnd(p, i); ok {
+				flushProse(i)
+				segments = append(segments, Segment{
+					Kind:  SegEditMLSpan,
+					Start: base + i,
+					End:   base + end + 1,
+					Text:  p[i : end+1],
+				})
+				i = end + 1
+				continue
+			}
+		}
+
+		char := p[i]
+		if char == '.' || char == '!' || char == '?' {
+			wordStart := strings.LastIndexAny(p[:i], " \n")
+			if wordStart == -1 {
+				wordStart = 0
+			} else {
+				wordStart++
+			}
+			word := p[wordStart : i+1]
+
+			if defaultAbbreviations[word] {
+				flushProse(wordStart)
+				segments = append(segments, Segment{
+					Kind:  SegAbbrev,
+					Start: base + wordStart,
+					End:   base + i + 1,
+					Text:  word,
+				})
+				i++
+				continue
+			}
+
+			if (char == '!' || char == '?') && i+1 < len(p) && p[i+1] == '"' {
+				j := i + 2
+				for j < len(p) && unicode.IsSpace(rune(p[j])) {
+					j++
+				}
+				if j < len(p) && unicode.IsLower(rune(p[j])) {
+					if proseStart < 0 {
+						proseStart = i
+					}
+					i++
+					continue
+				}
+			}
+
+			j := i + 1
+			for j < len(p) {
+				if strings.ContainsRune("*}_)]}\"'", rune(p[j])) {
+					j++
+				} else {
+					break
+				}
+			}
+
+			if j >= len(p) || unicode.IsSpace(rune(p[j])) {
+				flushProse(i)
+				segments = append(segments, Segment{
+					Kind:  SegSentenceEnd,
+					Start: base + i,
+					End:   base + j,
+				})
+				i = j
+				continue
+			}
+		}
+
+		if proseStart < 0 {
+			proseStart = i
+		}
+		i++
+	}
+	flushProse(len(p))
+
+	return segments
 }
 
 // IsVentilated detects whether the input string already follows the Advent reflow style.
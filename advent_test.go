@@ -1,7 +1,10 @@
 package advent
 
 import (
+	"bytes"
 	"errors"
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -53,10 +56,10 @@ func TestVentilate(t *testing.T) {
 			err:   nil,
 		},
 		{
-			name:  "Markdown Blockquote With Sentence Punctuation (No-op)",
+			name:  "Markdown Blockquote Reflows Per Quote Depth",
 			cfg:   Config{SentenceBreak: true},
 			input: "> This is a line. It should not break.",
-			want:  "> This is a line. It should not break.",
+			want:  "> This is a line.\n> It should not break.",
 			err:   nil,
 		},
 		{
@@ -170,6 +173,93 @@ func TestVentilate(t *testing.T) {
 			want:  "Item No. 42 is important.\nThe default Mr.\nSmith is not an abbreviation here.",
 			err:   nil,
 		},
+
+		// --- New tests for Knuth-Plass optimal line breaking ---
+		{
+			name: "Optimal: balances raggedness across the whole paragraph",
+			cfg: Config{
+				RespectMaxLineLength: true,
+				MaxLineLength:        20,
+				LineBreakAlgorithm:   Optimal,
+			},
+			input: "This is a long sentence that should wrap gently.",
+			want:  "This is a long sentence\nthat should wrap\ngently.",
+			err:   nil,
+		},
+		{
+			name: "Optimal: keeps an EditML span as a single unbreakable box",
+			cfg: Config{
+				RespectMaxLineLength: true,
+				MaxLineLength:        25,
+				LineBreakAlgorithm:   Optimal,
+			},
+			input: "This paragraph has an {+inserted note+} inside it that keeps going.",
+			want:  "This paragraph has an\n{+inserted note+} inside\nit that keeps going.",
+			err:   nil,
+		},
+		{
+			name: "Optimal: hyphenated breaks inside an overlong compound word",
+			cfg: Config{
+				RespectMaxLineLength: true,
+				MaxLineLength:        14,
+				LineBreakAlgorithm:   Optimal,
+				Hyphenated:           true,
+			},
+			input: "It is a state-of-the-art system.",
+			want:  "It is a state-of-\nthe-art system.",
+			err:   nil,
+		},
+
+		// --- New tests for quote-prefixed and format=flowed input ---
+		{
+			name:  "Nested blockquote reflows at its own quote depth",
+			cfg:   Config{SentenceBreak: true},
+			input: "> > Said one bird. Said another bird.",
+			want:  "> > Said one bird.\n> > Said another bird.",
+			err:   nil,
+		},
+		{
+			name: "FormatFlowed: joins soft-broken lines before ventilation",
+			cfg: Config{
+				SentenceBreak: true,
+				FormatFlowed:  true,
+			},
+			input: "This is a \nflowed sentence. Another one follows.",
+			want:  "This is a flowed sentence." + " " + "\nAnother one follows.",
+			err:   nil,
+		},
+		{
+			name: "FormatFlowed: unfolds and reflows a quoted reply",
+			cfg: Config{
+				SentenceBreak: true,
+				FormatFlowed:  true,
+			},
+			input: "> This is a \n> sentence. Here is \n> another one.",
+			want:  "> This is a sentence." + " " + "\n> Here is another one.",
+			err:   nil,
+		},
+		{
+			name: "FormatFlowed: space-stuffs a wrapped line starting with From",
+			cfg: Config{
+				RespectMaxLineLength: true,
+				MaxLineLength:        9,
+				FormatFlowed:         true,
+			},
+			input: "Greetings From HQ everyone is well today.",
+			want: "Greetings" + " " + "\n" +
+				" From HQ" + " " + "\n" +
+				"everyone" + " " + "\n" +
+				"is well" + " " + "\n" +
+				"today.",
+			err: nil,
+		},
+		{
+			name:  "Raw HTML block is left untouched, agreeing with Segmenter",
+			cfg:   Config{SentenceBreak: true},
+			input: "<div>This is some text. It has two sentences.</div>",
+			want:  "<div>This is some text. It has two sentences.</div>",
+			err:   nil,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -188,6 +278,115 @@ func TestVentilate(t *testing.T) {
 	}
 }
 
+// TestUnventilate runs a table-driven test covering the inverse of
+// TestVentilate's cases: collapsing ventilated prose back into flowing
+// paragraphs.
+func TestUnventilate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		cfg   Config
+		input string
+		want  string
+		err   error
+	}{
+		{
+			name:  "Simple ventilated paragraph collapses",
+			cfg:   Config{},
+			input: "This is a sentence.\nHere is another.",
+			want:  "This is a sentence. Here is another.",
+			err:   nil,
+		},
+		{
+			name:  "Quoted reply collapses within its quote depth",
+			cfg:   Config{},
+			input: "> This is a line.\n> It should not break.",
+			want:  "> This is a line. It should not break.",
+			err:   nil,
+		},
+		{
+			name:  "Nested blockquote collapses at its own quote depth",
+			cfg:   Config{},
+			input: "> > Said one bird.\n> > Said another bird.",
+			want:  "> > Said one bird. Said another bird.",
+			err:   nil,
+		},
+		{
+			name:  "Fenced code block is left untouched",
+			cfg:   Config{},
+			input: "```\nHere is some code. It does not need ventilation.\nprint(\"Hello world.\")\n```",
+			want:  "```\nHere is some code. It does not need ventilation.\nprint(\"Hello world.\")\n```",
+			err:   nil,
+		},
+		{
+			name:  "Markdown list is left untouched",
+			cfg:   Config{},
+			input: "- Found.\n- A dog.",
+			want:  "- Found.\n- A dog.",
+			err:   nil,
+		},
+		{
+			name:  "Multiple paragraphs collapse independently",
+			cfg:   Config{},
+			input: "Paragraph one.\nIt has two sentences.\n\nParagraph two.\nAlso two sentences.",
+			want:  "Paragraph one. It has two sentences.\n\nParagraph two. Also two sentences.",
+			err:   nil,
+		},
+		{
+			name: "Rewraps the collapsed paragraph to MaxLineLength",
+			cfg: Config{
+				RespectMaxLineLength: true,
+				MaxLineLength:        20,
+			},
+			input: "This is a long\nsentence that should\nwrap gently.",
+			want:  "This is a long\nsentence that should\nwrap gently.",
+			err:   nil,
+		},
+		{
+			name:  "Sentence ending in a colon still collapses into the paragraph",
+			cfg:   Config{},
+			input: "Consider the following:\nIt has two more sentences.\nHere is the third one.",
+			want:  "Consider the following: It has two more sentences. Here is the third one.",
+			err:   nil,
+		},
+		{
+			name:  "Error: Unterminated Markup",
+			cfg:   Config{},
+			input: "Something went wrong {+unfinished",
+			want:  "",
+			err:   errors.New("unterminated inline markup span"),
+		},
+		{
+			name:  "Round-trips with Ventilate on well-formed input",
+			cfg:   Config{SentenceBreak: true},
+			input: "This is the beginning.{+ And it continues.+}\nBut is it?",
+			want:  "This is the beginning.{+ And it continues.+} But is it?",
+			err:   nil,
+		},
+		{
+			name:  "Hyphen break from Optimal wrapping collapses with no stray space",
+			cfg:   Config{Hyphenated: true},
+			input: "It is a state-of-\nthe-art system.",
+			want:  "It is a state-of-the-art system.",
+			err:   nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Unventilate(tc.input, tc.cfg)
+
+			if (err != nil && tc.err == nil) || (err == nil && tc.err != nil) || (err != nil && tc.err != nil && err.Error() != tc.err.Error()) {
+				t.Errorf("Unventilate() error = %v, wantErr %v", err, tc.err)
+				return
+			}
+
+			if got != tc.want {
+				t.Errorf("Unventilate() got = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
 // TestIsVentilated tests the helper function for detecting ventilation.
 func TestIsVentilated(t *testing.T) {
 	testCases := []struct {
@@ -221,3 +420,226 @@ func TestIsVentilated(t *testing.T) {
 	}
 }
 
+// TestVentilateStream checks that streaming ventilation over an io.Reader
+// agrees with Ventilate on the same input, including trailing-newline
+// and CRLF handling.
+func TestVentilateStream(t *testing.T) {
+	testCases := []struct {
+		name  string
+		cfg   Config
+		input string
+	}{
+		{
+			name:  "Simple paragraph",
+			cfg:   Config{SentenceBreak: true},
+			input: "This is a sentence. Here is another.",
+		},
+		{
+			name:  "Trailing newline is preserved",
+			cfg:   Config{SentenceBreak: true},
+			input: "This is a sentence. Here is another.\n",
+		},
+		{
+			name:  "No trailing newline",
+			cfg:   Config{SentenceBreak: true},
+			input: "This is a sentence.",
+		},
+		{
+			name:  "Multiple blocks",
+			cfg:   Config{SentenceBreak: true},
+			input: "# Heading\n\nThis is a sentence. Here is another.\n\n- item one\n- item two\n",
+		},
+		{
+			name:  "CRLF line endings",
+			cfg:   Config{SentenceBreak: true},
+			input: "This is a sentence.\r\nHere is another.\r\n",
+		},
+		{
+			name:  "FormatFlowed: joins soft-broken lines before ventilation",
+			cfg:   Config{SentenceBreak: true, FormatFlowed: true},
+			input: "This is a \nflowed sentence. Another one follows.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			want, wantErr := Ventilate(tc.input, tc.cfg)
+
+			var buf bytes.Buffer
+			err := VentilateStream(strings.NewReader(tc.input), &buf, tc.cfg)
+
+			if (err != nil && wantErr == nil) || (err == nil && wantErr != nil) || (err != nil && wantErr != nil && err.Error() != wantErr.Error()) {
+				t.Fatalf("VentilateStream() error = %v, want %v", err, wantErr)
+			}
+			if buf.String() != want {
+				t.Errorf("VentilateStream() = %q, want %q", buf.String(), want)
+			}
+		})
+	}
+}
+
+// TestVentilateStreamUnterminatedMarkup checks that an unterminated
+// CriticMarkup/EditML span is still reported once the stream is
+// exhausted, even though it is never buffered in full.
+func TestVentilateStreamUnterminatedMarkup(t *testing.T) {
+	var buf bytes.Buffer
+	err := VentilateStream(strings.NewReader("{unterminated span"), &buf, Config{SentenceBreak: true})
+	if err != errUnterminatedMarkup {
+		t.Errorf("VentilateStream() error = %v, want %v", err, errUnterminatedMarkup)
+	}
+}
+
+// TestVentilatorReset checks that a Ventilator can be reconfigured and
+// reused for a new stream via Reset.
+func TestVentilatorReset(t *testing.T) {
+	v := NewVentilator(Config{SentenceBreak: true})
+
+	var buf1 bytes.Buffer
+	if err := v.Ventilate(strings.NewReader("One. Two."), &buf1); err != nil {
+		t.Fatalf("Ventilate() error = %v", err)
+	}
+	if want := "One.\nTwo."; buf1.String() != want {
+		t.Errorf("Ventilate() = %q, want %q", buf1.String(), want)
+	}
+
+	v.Reset(Config{RespectMaxLineLength: true, MaxLineLength: 40})
+
+	var buf2 bytes.Buffer
+	if err := v.Ventilate(strings.NewReader("Three. Four."), &buf2); err != nil {
+		t.Fatalf("Ventilate() error = %v", err)
+	}
+	if want := "Three. Four."; buf2.String() != want {
+		t.Errorf("Ventilate() = %q, want %q", buf2.String(), want)
+	}
+}
+
+// TestSegmenter checks that the Segmenter classifies blocks consistently
+// with how Ventilate and Unventilate treat the same input, and yields the
+// expected prose-level segments within a prose block.
+func TestSegmenter(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		want  []Segment
+	}{
+		{
+			name:  "Simple paragraph with two sentences",
+			input: "This is a sentence. Here is another.",
+			want: []Segment{
+				{Kind: SegProse, Start: 0, End: 18},
+				{Kind: SegSentenceEnd, Start: 18, End: 19},
+				{Kind: SegProse, Start: 19, End: 35},
+				{Kind: SegSentenceEnd, Start: 35, End: 36},
+			},
+		},
+		{
+			name:  "EditML span kept as a single unbreakable segment",
+			input: "This is the beginning.{+ And it continues.+} But is it?",
+			want: []Segment{
+				{Kind: SegProse, Start: 0, End: 22},
+				{Kind: SegEditMLSpan, Start: 22, End: 44, Text: "{+ And it continues.+}"},
+				{Kind: SegProse, Start: 44, End: 54},
+				{Kind: SegSentenceEnd, Start: 54, End: 55},
+			},
+		},
+		{
+			name:  "Abbreviation does not end a sentence",
+			input: "Dr. Smith arrived. He was late.",
+			want: []Segment{
+				{Kind: SegAbbrev, Start: 0, End: 3, Text: "Dr."},
+				{Kind: SegProse, Start: 3, End: 17},
+				{Kind: SegSentenceEnd, Start: 17, End: 18},
+				{Kind: SegProse, Start: 18, End: 30},
+				{Kind: SegSentenceEnd, Start: 30, End: 31},
+			},
+		},
+		{
+			name:  "Blocks classified by kind across a document",
+			input: "# Heading\n\nSome prose here.\n\n- item one\n- item two\n\n> Quoted line one.\n> Quoted line two.\n\n```\ncode here\n```\n",
+			want: []Segment{
+				{Kind: SegHeading, Start: 0, End: 9, Text: "# Heading"},
+				{Kind: SegBlankLine, Start: 10, End: 10},
+				{Kind: SegProse, Start: 11, End: 26},
+				{Kind: SegSentenceEnd, Start: 26, End: 27},
+				{Kind: SegBlankLine, Start: 28, End: 28},
+				{Kind: SegList, Start: 29, End: 50, Text: "- item one\n- item two"},
+				{Kind: SegBlankLine, Start: 51, End: 51},
+				{Kind: SegBlockquote, Start: 52, End: 89, Text: "> Quoted line one.\n> Quoted line two.", Depth: 1, Prefix: "> "},
+				{Kind: SegBlankLine, Start: 90, End: 90},
+				{Kind: SegFencedCode, Start: 91, End: 108, Text: "```\ncode here\n```"},
+				{Kind: SegBlankLine, Start: 109, End: 109},
+			},
+		},
+		{
+			name:  "Wrapped line break is not mistaken for a sentence break",
+			input: "This is a line\nthat continues here. And a second sentence.",
+			want: []Segment{
+				{Kind: SegProse, Start: 0, End: 34},
+				{Kind: SegSentenceEnd, Start: 34, End: 35},
+				{Kind: SegProse, Start: 35, End: 57},
+				{Kind: SegSentenceEnd, Start: 57, End: 58},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewSegmenter(tc.input)
+
+			var got []Segment
+			if err := s.Visit(func(seg Segment) error {
+				got = append(got, seg)
+				return nil
+			}); err != nil {
+				t.Fatalf("Visit() error = %v", err)
+			}
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d segments, want %d\ngot:  %+v\nwant: %+v", len(got), len(tc.want), got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("segment %d = %+v, want %+v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestSegmenterNext checks Next's step-by-step iteration and its io.EOF
+// sentinel once the stream is exhausted.
+func TestSegmenterNext(t *testing.T) {
+	s := NewSegmenter("Hi.")
+
+	first, err := s.Next()
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if first.Kind != SegProse || first.Start != 0 || first.End != 2 {
+		t.Errorf("first segment = %+v, want SegProse [0:2]", first)
+	}
+
+	if _, err := s.Next(); err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+
+	if _, err := s.Next(); err != io.EOF {
+		t.Errorf("Next() at end error = %v, want io.EOF", err)
+	}
+}
+
+// TestSegmenterUnterminatedMarkup checks that, like Ventilate and
+// Unventilate, a Segmenter rejects an unterminated CriticMarkup/EditML
+// span rather than silently segmenting the stray brace as prose.
+func TestSegmenterUnterminatedMarkup(t *testing.T) {
+	s := NewSegmenter("Hello {+note that never closes")
+
+	if _, err := s.Next(); err == nil || err.Error() != "unterminated inline markup span" {
+		t.Fatalf("Next() error = %v, want unterminated inline markup span", err)
+	}
+
+	if _, err := s.Next(); err == nil || err.Error() != "unterminated inline markup span" {
+		t.Errorf("Next() on a second call error = %v, want unterminated inline markup span", err)
+	}
+}
+
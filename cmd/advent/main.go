@@ -19,6 +19,9 @@ func main() {
 	pSpacing := flag.String("paragraph-spacing", "single", "Paragraph spacing ('single' or 'blank-line').")
 	respectMaxLine := flag.Bool("respect-max-line-length", false, "Respect max line length for soft wrapping.")
 	abbrevs := flag.String("abbreviations", "", "Comma-separated list of custom abbreviations (e.g., \"No.,Fig.\").")
+	lineBreakAlgorithm := flag.String("line-break-algorithm", "greedy", "Word-wrapping strategy for max-line-length ('greedy' or 'optimal').")
+	formatFlowed := flag.Bool("format-flowed", false, "Treat input as format=flowed (RFC 3676) mail text.")
+	unventilate := flag.Bool("unventilate", false, "Collapse ventilated prose back into flowing paragraphs instead of ventilating.")
 
 	// Custom usage message to provide more context and examples.
 	flag.Usage = func() {
@@ -36,20 +39,15 @@ func main() {
 
 	flag.Parse()
 
-	var inputBytes []byte
-	var err error
-
 	// Read from the specified input file or fall back to stdin.
+	var input io.Reader = os.Stdin
 	if *inputFile != "" {
-		inputBytes, err = os.ReadFile(*inputFile)
+		f, err := os.Open(*inputFile)
 		if err != nil {
 			log.Fatalf("Error reading from file %q: %v", *inputFile, err)
 		}
-	} else {
-		inputBytes, err = io.ReadAll(os.Stdin)
-		if err != nil {
-			log.Fatalf("Error reading from stdin: %v", err)
-		}
+		defer f.Close()
+		input = f
 	}
 
 	// Build the configuration from the flags.
@@ -58,6 +56,16 @@ func main() {
 		MaxLineLength:        *maxLineLength,
 		ParagraphSpacing:     *pSpacing,
 		RespectMaxLineLength: *respectMaxLine,
+		FormatFlowed:         *formatFlowed,
+	}
+
+	switch *lineBreakAlgorithm {
+	case "optimal":
+		cfg.LineBreakAlgorithm = advent.Optimal
+	case "greedy":
+		cfg.LineBreakAlgorithm = advent.Greedy
+	default:
+		log.Fatalf("Unknown line-break-algorithm %q: must be \"greedy\" or \"optimal\"", *lineBreakAlgorithm)
 	}
 
 	// If custom abbreviations are provided, parse them into the config map.
@@ -72,13 +80,30 @@ func main() {
 		}
 	}
 
-	// Ventilate the input using the library.
-	output, err := advent.Ventilate(string(inputBytes), cfg)
-	if err != nil {
-		log.Fatalf("Error ventilating text: %v", err)
+	// Unventilate still operates on the whole document in memory;
+	// ventilation streams block by block so large documents never need to
+	// be buffered in full.
+	if *unventilate {
+		inputBytes, err := io.ReadAll(input)
+		if err != nil {
+			log.Fatalf("Error reading input: %v", err)
+		}
+		output, err := advent.Unventilate(string(inputBytes), cfg)
+		if err != nil {
+			log.Fatalf("Error processing text: %v", err)
+		}
+		fmt.Println(output)
+		return
 	}
 
-	// Write the result to standard output.
-	fmt.Println(output)
+	// VentilateStream writes each block to stdout as soon as it is
+	// ventilated, so large documents are never buffered in full. As
+	// VentilateStream itself documents, an unterminated CriticMarkup/EditML
+	// span is only reported once the input is exhausted, so on that error
+	// any earlier well-formed blocks have already been printed.
+	if err := advent.VentilateStream(input, os.Stdout, cfg); err != nil {
+		log.Fatalf("Error processing text: %v", err)
+	}
+	fmt.Println()
 }
 